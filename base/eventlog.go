@@ -0,0 +1,67 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// JSONEventLogger writes every ValueEvent as a single JSON line to an
+// io.Writer, for ingestion by external tools (e.g. tailing a log file into
+// a log aggregator). Safe for concurrent use; writes are serialised so
+// lines from different modules never interleave.
+type JSONEventLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEventLogger creates a logger writing newline-delimited JSON to w.
+// Pass the result's Observe method to SetObserver:
+//
+//	base.SetObserver(base.NewJSONEventLogger(os.Stderr).Observe)
+func NewJSONEventLogger(w io.Writer) *JSONEventLogger {
+	return &JSONEventLogger{enc: json.NewEncoder(w)}
+}
+
+type jsonValueEvent struct {
+	Module    string      `json:"module,omitempty"`
+	Kind      string      `json:"kind"`
+	Value     interface{} `json:"value,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// Observe implements the observer function signature expected by
+// SetObserver.
+func (j *JSONEventLogger) Observe(ev ValueEvent) {
+	e := jsonValueEvent{
+		Module:    ev.ModuleLabel,
+		Kind:      ev.Kind.String(),
+		Value:     ev.Value,
+		Timestamp: ev.Timestamp.Format(rfc3339Micro),
+	}
+	if ev.Err != nil {
+		e.Error = ev.Err.Error()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// Best-effort: there's no reasonable way to surface a write failure to
+	// whatever module triggered the event that's being logged.
+	_ = j.enc.Encode(e)
+}
+
+const rfc3339Micro = "2006-01-02T15:04:05.000000Z07:00"
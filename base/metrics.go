@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"expvar"
+	"sync"
+)
+
+// ModuleMetrics counts Set/Error events per module label and publishes
+// them through the standard expvar mechanism, so they show up alongside
+// any other process metrics at /debug/vars without a bespoke endpoint.
+type ModuleMetrics struct {
+	mu     sync.Mutex
+	sets   map[string]int64
+	errors map[string]int64
+}
+
+// NewModuleMetrics creates a metrics collector and publishes it under name
+// via expvar.Publish. Pass the result's Observe method to SetObserver:
+//
+//	base.SetObserver(base.NewModuleMetrics("barista_modules").Observe)
+//
+// expvar.Publish panics if name is already registered; NewModuleMetrics
+// skips the Publish call instead of panicking if something (most often a
+// second call with the same name, e.g. from a test) got there first, since
+// a duplicate name is a naming mistake, not a reason to crash the process.
+func NewModuleMetrics(name string) *ModuleMetrics {
+	m := &ModuleMetrics{sets: map[string]int64{}, errors: map[string]int64{}}
+	if expvar.Get(name) == nil {
+		expvar.Publish(name, expvar.Func(func() interface{} { return m.snapshot() }))
+	}
+	return m
+}
+
+func (m *ModuleMetrics) snapshot() map[string]map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sets := make(map[string]int64, len(m.sets))
+	for k, v := range m.sets {
+		sets[k] = v
+	}
+	errors := make(map[string]int64, len(m.errors))
+	for k, v := range m.errors {
+		errors[k] = v
+	}
+	return map[string]map[string]int64{"sets": sets, "errors": errors}
+}
+
+// Observe implements the observer function signature expected by
+// SetObserver, counting EventSet and EventError occurrences per module.
+func (m *ModuleMetrics) Observe(ev ValueEvent) {
+	label := ev.ModuleLabel
+	if label == "" {
+		label = "(unlabeled)"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch ev.Kind {
+	case EventSet:
+		m.sets[label]++
+	case EventError:
+		m.errors[label]++
+	}
+}
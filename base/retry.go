@@ -0,0 +1,206 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/soumya92/barista"
+	"github.com/soumya92/barista/bar"
+	l "github.com/soumya92/barista/logging"
+)
+
+// RetryOptions configures the exponential backoff used by Retry. Any field
+// left at its zero value gets the documented default.
+type RetryOptions struct {
+	// InitialInterval is the delay before the first retry after a failure.
+	// Defaults to 500ms.
+	InitialInterval time.Duration
+	// Multiplier scales the interval after each successive failure.
+	// Defaults to 1.5.
+	Multiplier float64
+	// RandomizationFactor jitters each interval by +/- this fraction, so
+	// that many instances of the same flaky module don't retry in lockstep.
+	// Defaults to 0.5.
+	RandomizationFactor float64
+	// MaxInterval caps the backoff interval. Defaults to 30s.
+	MaxInterval time.Duration
+	// MaxElapsedTime stops retrying, leaving the last error in place, once
+	// this much time has passed since the most recent success (or since
+	// Retry was called, if it has never succeeded) -- so it bounds how
+	// long a single outage can be retried, not total process uptime. Zero
+	// (the default) means retry forever.
+	MaxElapsedTime time.Duration
+	// Label identifies the returned Retryable in ValueEvents fired for its
+	// Set/Error/Subscribe, the same way logging.Label does for any other
+	// module state. Left empty, events from it show up unlabelled.
+	Label string
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.InitialInterval <= 0 {
+		o.InitialInterval = 500 * time.Millisecond
+	}
+	if o.Multiplier <= 0 {
+		o.Multiplier = 1.5
+	}
+	if o.RandomizationFactor <= 0 {
+		o.RandomizationFactor = 0.5
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = 30 * time.Second
+	}
+	return o
+}
+
+// backoff produces successive exponential-with-jitter retry intervals,
+// capped at opts.MaxInterval, resettable back to opts.InitialInterval.
+type backoff struct {
+	opts    RetryOptions
+	current time.Duration
+}
+
+func (b *backoff) next() time.Duration {
+	interval := b.current
+	scaled := time.Duration(float64(b.current) * b.opts.Multiplier)
+	if scaled > b.opts.MaxInterval {
+		scaled = b.opts.MaxInterval
+	}
+	b.current = scaled
+	delta := b.opts.RandomizationFactor * float64(interval)
+	return interval + time.Duration((rand.Float64()*2-1)*delta)
+}
+
+func (b *backoff) reset() {
+	b.current = b.opts.InitialInterval
+}
+
+// retryState is what Retryable actually stores: the last value op returned
+// successfully, alongside whatever error (if any) the most recent attempt
+// produced. Unlike ErrorValue, the two aren't mutually exclusive -- a
+// failed attempt keeps the previous value in place instead of discarding
+// it, so callers can tell the difference between "never succeeded" and
+// "succeeded before, but the latest refresh failed".
+type retryState struct {
+	value interface{}
+	err   error
+}
+
+// Retryable is the handle returned by Retry. It adds the ability to force
+// an immediate retry or stop retrying altogether.
+type Retryable struct {
+	state   Value // of retryState
+	resetCh chan struct{}
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+// Get returns the last value op returned successfully, and the error (if
+// any) from the most recent attempt. If the most recent attempt failed,
+// both a non-nil value (the last good one) and a non-nil err can be
+// returned together, so callers can keep displaying the last good value
+// while flagging it as stale.
+func (r *Retryable) Get() (interface{}, error) {
+	s, _ := r.state.Get().(retryState)
+	return s.value, s.err
+}
+
+// Subscribe creates a new ticker for updates to r, fired on every attempt
+// regardless of whether it succeeded or failed.
+func (r *Retryable) Subscribe() bar.Ticker {
+	n := r.state.subscribe()
+	fire(r, EventSubscribe, nil, nil)
+	return n
+}
+
+// Reset forces an immediate retry attempt, as if the current backoff
+// interval had already elapsed. Safe to call from a click handler.
+func (r *Retryable) Reset() {
+	select {
+	case r.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+// Stop cancels any pending retry and tears down its scheduler tick. Modules
+// must call this when their subscriber goes away (e.g. alongside
+// unsubscribing from a netlink watcher), so a flaky data source doesn't
+// keep rescheduling itself forever in the background.
+func (r *Retryable) Stop() {
+	r.stopped.Do(func() { close(r.stopCh) })
+}
+
+// Retry runs op immediately and stores the result in the returned handle.
+// If op returns an error, it's retried with exponential backoff -- each
+// attempt scheduled via barista.Schedule(), never a busy loop -- until it
+// succeeds, or until opts.MaxElapsedTime has passed since the last success
+// (0, the default, means retry forever). Once op succeeds, the backoff and
+// the MaxElapsedTime clock both reset and op is attempted again at
+// opts.InitialInterval, so Retry also serves as a self-healing poller: a
+// data source that degrades (longer and longer backoff) quietly recovers
+// to its normal cadence once it works again, and a bounded MaxElapsedTime
+// only ever measures a single outage, not the module's total uptime.
+func Retry(op func() (interface{}, error), opts RetryOptions) *Retryable {
+	opts = opts.withDefaults()
+	r := &Retryable{
+		resetCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}
+	if opts.Label != "" {
+		l.Label(r, opts.Label)
+	}
+	go r.run(op, opts)
+	return r
+}
+
+func (r *Retryable) run(op func() (interface{}, error), opts RetryOptions) {
+	b := &backoff{opts: opts, current: opts.InitialInterval}
+	start := time.Now()
+	sched := barista.Schedule()
+	defer sched.Stop()
+
+	var lastGood interface{}
+	for {
+		value, err := op()
+		if err == nil {
+			lastGood = value
+			b.reset()
+			start = time.Now()
+		}
+		r.state.set(retryState{value: lastGood, err: err})
+		if err == nil {
+			fire(r, EventSet, value, nil)
+		} else {
+			fire(r, EventError, nil, err)
+			if opts.MaxElapsedTime > 0 && time.Since(start) > opts.MaxElapsedTime {
+				return
+			}
+		}
+
+		interval := opts.InitialInterval
+		if err != nil {
+			interval = b.next()
+		}
+		sched.After(interval)
+		select {
+		case <-sched.Tick():
+		case <-r.resetCh:
+		case <-r.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffSequence(t *testing.T) {
+	// RandomizationFactor 0 makes next() deterministic, so the
+	// multiplier/cap math can be checked exactly. opts is used as given:
+	// withDefaults would replace the zero RandomizationFactor with 0.5.
+	opts := RetryOptions{
+		InitialInterval:     10 * time.Millisecond,
+		Multiplier:          2,
+		RandomizationFactor: 0,
+		MaxInterval:         50 * time.Millisecond,
+	}
+	b := &backoff{opts: opts, current: opts.InitialInterval}
+
+	want := []time.Duration{
+		10 * time.Millisecond, // current starts at InitialInterval
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond, // capped at MaxInterval
+		50 * time.Millisecond, // stays capped
+	}
+	for i, w := range want {
+		if got := b.next(); got != w {
+			t.Errorf("next() #%d = %v, want %v", i, got, w)
+		}
+	}
+
+	b.reset()
+	if b.current != opts.InitialInterval {
+		t.Errorf("after reset, current = %v, want %v", b.current, opts.InitialInterval)
+	}
+}
+
+func TestBackoffJitterWithinBounds(t *testing.T) {
+	opts := RetryOptions{
+		InitialInterval:     100 * time.Millisecond,
+		Multiplier:          1,
+		RandomizationFactor: 0.5,
+		MaxInterval:         time.Second,
+	}.withDefaults()
+	b := &backoff{opts: opts, current: opts.InitialInterval}
+	for i := 0; i < 20; i++ {
+		got := b.next()
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Errorf("next() = %v, want within +/-50%% of 100ms", got)
+		}
+		b.current = opts.InitialInterval // multiplier is 1; isolate jitter only
+	}
+}
+
+func TestRetryableGetReflectsLastGoodValueAndLiveError(t *testing.T) {
+	var calls int32
+	errBoom := errBoomSentinel{}
+	r := Retry(func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			return "first", nil
+		}
+		return nil, errBoom
+	}, RetryOptions{InitialInterval: 5 * time.Millisecond, MaxInterval: 5 * time.Millisecond})
+	defer r.Stop()
+
+	waitForCalls(t, &calls, 2)
+
+	v, err := r.Get()
+	if v != "first" {
+		t.Errorf("Get() value = %v, want %q (the last good value)", v, "first")
+	}
+	if err != errBoom {
+		t.Errorf("Get() err = %v, want %v", err, errBoom)
+	}
+}
+
+func TestRetryableResetTriggersImmediateAttempt(t *testing.T) {
+	var calls int32
+	r := Retry(func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errBoomSentinel{}
+	}, RetryOptions{InitialInterval: time.Hour}) // long enough that only Reset should trigger #2
+	defer r.Stop()
+
+	waitForCalls(t, &calls, 1)
+	r.Reset()
+	waitForCalls(t, &calls, 2)
+}
+
+func TestRetryableStopHaltsFurtherAttempts(t *testing.T) {
+	var calls int32
+	r := Retry(func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errBoomSentinel{}
+	}, RetryOptions{InitialInterval: 5 * time.Millisecond, MaxInterval: 5 * time.Millisecond})
+
+	waitForCalls(t, &calls, 1)
+	r.Stop()
+	after := atomic.LoadInt32(&calls)
+	time.Sleep(50 * time.Millisecond)
+	if now := atomic.LoadInt32(&calls); now != after {
+		t.Errorf("calls kept increasing after Stop: %d -> %d", after, now)
+	}
+}
+
+// TestRetryableMaxElapsedTimeSurvivesLongSuccessStreak is a regression test
+// for start never being refreshed: without resetting it on success, an
+// isolated failure arriving long after Retry was first called (but right
+// after a success) would look like it was past MaxElapsedTime and stop
+// retrying for good.
+func TestRetryableMaxElapsedTimeSurvivesLongSuccessStreak(t *testing.T) {
+	var calls int32
+	var failing int32
+	r := Retry(func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) != 0 {
+			return nil, errBoomSentinel{}
+		}
+		return n, nil
+	}, RetryOptions{
+		InitialInterval: 5 * time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		MaxElapsedTime:  20 * time.Millisecond,
+	})
+	defer r.Stop()
+
+	// Let it succeed for well over MaxElapsedTime before the first failure.
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&failing, 1)
+
+	before := atomic.LoadInt32(&calls)
+	waitForCallsAbove(t, &calls, before)
+	// It must still be retrying a bit later too, not just once more before
+	// hitting the (wrongly long-elapsed) MaxElapsedTime cutoff.
+	afterFirstFailure := atomic.LoadInt32(&calls)
+	waitForCallsAbove(t, &calls, afterFirstFailure)
+}
+
+type errBoomSentinel struct{}
+
+func (errBoomSentinel) Error() string { return "boom" }
+
+func waitForCalls(t *testing.T, calls *int32, want int32) {
+	t.Helper()
+	waitForCallsAbove(t, calls, want-1)
+}
+
+func waitForCallsAbove(t *testing.T, calls *int32, floor int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(calls) > floor {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("calls did not exceed %d within 1s (got %d)", floor, atomic.LoadInt32(calls))
+}
@@ -18,8 +18,10 @@ package base
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/soumya92/barista/bar"
+	l "github.com/soumya92/barista/logging"
 	"github.com/soumya92/barista/notifier"
 )
 
@@ -33,6 +35,12 @@ type Value struct {
 
 // Subscribe creates a new ticker for value updates.
 func (v *Value) Subscribe() bar.Ticker {
+	n := v.subscribe()
+	fire(v, EventSubscribe, nil, nil)
+	return n
+}
+
+func (v *Value) subscribe() bar.Ticker {
 	n := notifier.New()
 	v.subM.Lock()
 	defer v.subM.Unlock()
@@ -47,6 +55,11 @@ func (v *Value) Get() interface{} {
 
 // Set updates the stored values and notifies any subscribers.
 func (v *Value) Set(value interface{}) {
+	v.set(value)
+	fire(v, EventSet, value, nil)
+}
+
+func (v *Value) set(value interface{}) {
 	v.value.Store(value)
 	v.subM.RLock()
 	defer v.subM.RUnlock()
@@ -68,7 +81,9 @@ type ErrorValue struct {
 
 // Subscribe creates a new ticker for value/error updates.
 func (e *ErrorValue) Subscribe() bar.Ticker {
-	return e.v.Subscribe()
+	n := e.v.subscribe()
+	fire(e, EventSubscribe, nil, nil)
+	return n
 }
 
 // Get returns the currently stored value or error.
@@ -82,7 +97,8 @@ func (e *ErrorValue) Get() (interface{}, error) {
 
 // Set updates the stored value and clears any error.
 func (e *ErrorValue) Set(value interface{}) {
-	e.v.Set(valueOrErr{value: value})
+	e.v.set(valueOrErr{value: value})
+	fire(e, EventSet, value, nil)
 }
 
 // Error replaces the stored value and returns true if non-nil,
@@ -91,6 +107,89 @@ func (e *ErrorValue) Error(err error) bool {
 	if err == nil {
 		return false
 	}
-	e.v.Set(valueOrErr{err: err})
+	e.v.set(valueOrErr{err: err})
+	fire(e, EventError, nil, err)
 	return true
 }
+
+// EventKind identifies the kind of change a ValueEvent describes.
+//
+// There's deliberately no EventUnsubscribe: Value/ErrorValue/Retryable have
+// no unsubscribe method (a bar.Ticker is just dropped, not explicitly torn
+// down) and nothing to fire it from, so it's left out rather than added as
+// a kind no code path can ever produce.
+type EventKind int
+
+const (
+	// EventSet means a Value or ErrorValue was given a new value via Set.
+	EventSet EventKind = iota
+	// EventError means an ErrorValue was given a non-nil error.
+	EventError
+	// EventSubscribe means something subscribed to a Value or ErrorValue
+	// for update notifications.
+	EventSubscribe
+)
+
+// String returns a human-readable name for the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventError:
+		return "error"
+	case EventSubscribe:
+		return "subscribe"
+	default:
+		return "unknown"
+	}
+}
+
+// ValueEvent describes a single state change observed on a Value or
+// ErrorValue, for debugging things like "why did my bar module stop
+// updating" without printf-logging every module.
+type ValueEvent struct {
+	// ModuleLabel identifies the owning Value/ErrorValue, using whatever
+	// label was given to it via logging.Label/logging.Register. Empty if
+	// the field in question was never registered.
+	ModuleLabel string
+	Kind        EventKind
+	Value       interface{}
+	Err         error
+	Timestamp   time.Time
+}
+
+// observer, if set, is notified of every Set/Error/Subscribe across every
+// Value and ErrorValue in the process. It's stored behind a single atomic
+// pointer so that with no observer registered -- the common case -- the
+// hot path is one atomic load and a nil check, with no allocation.
+var observer atomic.Value // of *func(ValueEvent)
+
+// SetObserver installs a process-wide hook called for every
+// Set/Error/Subscribe on every module's Value and ErrorValue. Pass nil to
+// remove it. obs is called synchronously from whatever goroutine made the
+// change, so it should be cheap, or hand off to a queue of its own.
+func SetObserver(obs func(ValueEvent)) {
+	observer.Store(&obs)
+}
+
+func currentObserver() func(ValueEvent) {
+	o, ok := observer.Load().(*func(ValueEvent))
+	if !ok || o == nil {
+		return nil
+	}
+	return *o
+}
+
+func fire(owner interface{}, kind EventKind, value interface{}, err error) {
+	obs := currentObserver()
+	if obs == nil {
+		return
+	}
+	obs(ValueEvent{
+		ModuleLabel: l.ID(owner),
+		Kind:        kind,
+		Value:       value,
+		Err:         err,
+		Timestamp:   time.Now(),
+	})
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// withObserver installs obs for the duration of the test and guarantees
+// it's torn down afterwards, since observer is process-global state.
+func withObserver(t *testing.T, obs func(ValueEvent)) {
+	t.Helper()
+	SetObserver(obs)
+	t.Cleanup(func() { SetObserver(nil) })
+}
+
+func TestValueSetFiresObserver(t *testing.T) {
+	var got []ValueEvent
+	withObserver(t, func(ev ValueEvent) { got = append(got, ev) })
+
+	var v Value
+	v.Set(42)
+
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	if got[0].Kind != EventSet || got[0].Value != 42 {
+		t.Errorf("event = %+v, want Kind=EventSet Value=42", got[0])
+	}
+}
+
+func TestValueSubscribeFiresObserver(t *testing.T) {
+	var got []ValueEvent
+	withObserver(t, func(ev ValueEvent) { got = append(got, ev) })
+
+	var v Value
+	v.Subscribe()
+
+	if len(got) != 1 || got[0].Kind != EventSubscribe {
+		t.Fatalf("got %+v, want a single EventSubscribe", got)
+	}
+}
+
+func TestErrorValueSetAndErrorFireObserver(t *testing.T) {
+	var got []ValueEvent
+	withObserver(t, func(ev ValueEvent) { got = append(got, ev) })
+
+	var e ErrorValue
+	e.Set("ok")
+	boom := errors.New("boom")
+	if !e.Error(boom) {
+		t.Fatal("Error(non-nil) should return true")
+	}
+	if e.Error(nil) {
+		t.Fatal("Error(nil) should return false")
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2 (Error(nil) shouldn't fire)", len(got))
+	}
+	if got[0].Kind != EventSet || got[0].Value != "ok" {
+		t.Errorf("event 0 = %+v, want Kind=EventSet Value=ok", got[0])
+	}
+	if got[1].Kind != EventError || got[1].Err != boom {
+		t.Errorf("event 1 = %+v, want Kind=EventError Err=%v", got[1], boom)
+	}
+}
+
+func TestNoObserverDoesNotPanic(t *testing.T) {
+	SetObserver(nil)
+	var v Value
+	v.Set(1) // must be a no-op, not a nil dereference
+}
+
+func TestModuleMetricsObserve(t *testing.T) {
+	m := NewModuleMetrics(t.Name())
+	boom := errors.New("boom")
+
+	m.Observe(ValueEvent{ModuleLabel: "wlan.wlan0", Kind: EventSet})
+	m.Observe(ValueEvent{ModuleLabel: "wlan.wlan0", Kind: EventSet})
+	m.Observe(ValueEvent{ModuleLabel: "wlan.wlan0", Kind: EventError, Err: boom})
+	m.Observe(ValueEvent{Kind: EventSet}) // unlabelled
+	m.Observe(ValueEvent{ModuleLabel: "wlan.wlan0", Kind: EventSubscribe})
+
+	snap := m.snapshot()
+	if snap["sets"]["wlan.wlan0"] != 2 {
+		t.Errorf("sets[wlan.wlan0] = %d, want 2", snap["sets"]["wlan.wlan0"])
+	}
+	if snap["errors"]["wlan.wlan0"] != 1 {
+		t.Errorf("errors[wlan.wlan0] = %d, want 1", snap["errors"]["wlan.wlan0"])
+	}
+	if snap["sets"]["(unlabeled)"] != 1 {
+		t.Errorf("sets[(unlabeled)] = %d, want 1", snap["sets"]["(unlabeled)"])
+	}
+}
+
+func TestNewModuleMetricsDoesNotPanicOnDuplicateName(t *testing.T) {
+	NewModuleMetrics(t.Name())
+	NewModuleMetrics(t.Name()) // must not panic on the duplicate expvar name
+}
+
+func TestJSONEventLoggerObserve(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONEventLogger(&buf)
+	boom := errors.New("boom")
+
+	logger.Observe(ValueEvent{ModuleLabel: "netspeed.eth0", Kind: EventSet, Value: 7})
+	logger.Observe(ValueEvent{ModuleLabel: "netspeed.eth0", Kind: EventError, Err: boom})
+
+	dec := json.NewDecoder(&buf)
+	var lines []jsonValueEvent
+	for dec.More() {
+		var e jsonValueEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		lines = append(lines, e)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0].Module != "netspeed.eth0" || lines[0].Kind != "set" {
+		t.Errorf("line 0 = %+v", lines[0])
+	}
+	if lines[1].Error != "boom" || lines[1].Kind != "error" {
+		t.Errorf("line 1 = %+v", lines[1])
+	}
+}
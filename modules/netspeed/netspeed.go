@@ -19,18 +19,23 @@ import (
 	"time"
 
 	"github.com/martinlindhe/unit"
-	"github.com/vishvananda/netlink"
+	rtnetlink "github.com/vishvananda/netlink"
 
 	"github.com/soumya92/barista"
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/watchers/netlink"
 	"github.com/soumya92/barista/outputs"
 	"github.com/soumya92/barista/scheduler"
 )
 
-// Speeds represents bidirectional network traffic.
+// Speeds represents bidirectional network traffic, possibly aggregated
+// across more than one interface.
 type Speeds struct {
 	Rx, Tx unit.Datarate
+	// SpeedsByInterface breaks the totals above down per interface, keyed
+	// by interface name, for modules tracking more than one link.
+	SpeedsByInterface map[string]Speeds
 	// Keep track of whether these speeds are actually 0
 	// or uninitialised.
 	available bool
@@ -41,6 +46,25 @@ func (s Speeds) Total() unit.Datarate {
 	return s.Rx + s.Tx
 }
 
+// Primary returns the name and speeds of whichever tracked interface
+// currently has the highest total throughput, for templates that want a
+// single "which link am I using right now" indicator instead of the full
+// per-interface breakdown. name is empty if no interface is present. Ties
+// (most commonly every interface idle at 0bps) are broken by interface
+// name rather than map iteration order, so the result doesn't flicker
+// between interfaces from one tick to the next with nothing having changed.
+func (s Speeds) Primary() (name string, speeds Speeds) {
+	var best unit.Datarate
+	haveBest := false
+	for n, sp := range s.SpeedsByInterface {
+		t := sp.Total()
+		if !haveBest || t > best || (t == best && n < name) {
+			best, name, speeds, haveBest = t, n, sp, true
+		}
+	}
+	return
+}
+
 // Module represents a netspeed bar module. It supports setting the output
 // format, click handler, and update frequency.
 type Module interface {
@@ -60,15 +84,38 @@ type Module interface {
 
 type module struct {
 	base.SimpleClickHandler
-	iface      string
+	subscribe  func() netlink.Subscription
 	scheduler  bar.Scheduler
 	outputFunc base.Value // of func(Speeds) bar.Output
+
+	// refreshInterval is the configured polling frequency, also used as
+	// the poll interval for each interface's underlying counter read (see
+	// RefreshInterval), so a faster-than-default refresh doesn't end up
+	// reading the same cached counters on every other tick.
+	refreshInterval time.Duration
 }
 
 // New constructs an instance of the netspeed module for the given interface.
 func New(iface string) Module {
+	return newModule(func() netlink.Subscription { return netlink.ByName(iface) })
+}
+
+// Any constructs an instance of the netspeed module that aggregates
+// throughput across every network interface present on the system, useful
+// on laptops that hop between e.g. eth0/wlan0/usb0.
+func Any() Module {
+	return WithPrefix("")
+}
+
+// WithPrefix constructs an instance of the netspeed module that aggregates
+// throughput across every interface whose name starts with prefix.
+func WithPrefix(prefix string) Module {
+	return newModule(func() netlink.Subscription { return netlink.WithPrefix(prefix) })
+}
+
+func newModule(subscribe func() netlink.Subscription) Module {
 	m := &module{
-		iface:     iface,
+		subscribe: subscribe,
 		scheduler: barista.Schedule(),
 	}
 	// Default is to refresh every 3s, similar to top.
@@ -91,6 +138,7 @@ func (m *module) OutputTemplate(template func(interface{}) bar.Output) Module {
 
 func (m *module) RefreshInterval(interval time.Duration) Module {
 	m.scheduler.Every(interval)
+	m.refreshInterval = interval
 	return m
 }
 
@@ -101,13 +149,38 @@ func (m *module) Stream() <-chan bar.Output {
 }
 
 // For tests.
-var linkByName = netlink.LinkByName
+var linkByName = rtnetlink.LinkByName
+
+// ifaceCounters is the last known byte counters for a tracked interface,
+// used to compute a delta against the next poll.
+type ifaceCounters struct {
+	rx, tx uint64
+}
 
 func (m *module) worker(ch base.Channel) {
-	lastRx, lastTx, err := linkRxTx(m.iface)
-	if ch.Error(err) {
-		return
+	updateChan := m.subscribe()
+	defer updateChan.Unsubscribe()
+
+	present := map[string]bool{}
+	last := map[string]ifaceCounters{}
+
+	// Each present interface gets its own retryable read of linkRxTx, so a
+	// transient netlink error (e.g. the device is momentarily busy) backs
+	// off and recovers instead of permanently dropping the interface until
+	// it happens to disappear and reappear.
+	retries := map[string]*base.Retryable{}
+	stopRetry := func(iface string) {
+		if r, ok := retries[iface]; ok {
+			r.Stop()
+			delete(retries, iface)
+		}
 	}
+	defer func() {
+		for iface := range retries {
+			stopRetry(iface)
+		}
+	}()
+
 	lastRead := scheduler.Now()
 
 	var speeds Speeds
@@ -119,29 +192,72 @@ func (m *module) worker(ch base.Channel) {
 			ch.Output(outputFunc(speeds))
 		}
 		select {
+		case update := <-updateChan:
+			if update.State <= netlink.NotPresent {
+				// The interface is gone; its counters must not be used as
+				// the baseline for whatever interface reuses the name next.
+				delete(present, update.Name)
+				delete(last, update.Name)
+				stopRetry(update.Name)
+			} else if !present[update.Name] {
+				present[update.Name] = true
+				iface := update.Name
+				retries[iface] = base.Retry(func() (interface{}, error) {
+					rx, tx, err := linkRxTx(iface)
+					return ifaceCounters{rx: rx, tx: tx}, err
+				}, base.RetryOptions{InitialInterval: m.refreshInterval, Label: "netspeed." + iface})
+			}
+
 		case <-sOutputFunc.Tick():
 			outputFunc = m.outputFunc.Get().(func(Speeds) bar.Output)
+
 		case <-m.scheduler.Tick():
-			rx, tx, err := linkRxTx(m.iface)
-			if ch.Error(err) {
-				return
-			}
 			now := scheduler.Now()
 			duration := now.Sub(lastRead).Seconds()
 
-			speeds.available = true
-			speeds.Rx = unit.Datarate(float64(rx-lastRx)/duration) * unit.BytePerSecond
-			speeds.Tx = unit.Datarate(float64(tx-lastTx)/duration) * unit.BytePerSecond
+			bySpeed := map[string]Speeds{}
+			var totalRx, totalTx unit.Datarate
+			for iface := range present {
+				r, ok := retries[iface]
+				if !ok {
+					continue
+				}
+				v, err := r.Get()
+				if err != nil {
+					// Read is still failing; keep retrying in the
+					// background rather than reporting stale or zero data.
+					continue
+				}
+				counters, ok := v.(ifaceCounters)
+				if !ok {
+					continue
+				}
+				if prev, ok := last[iface]; ok {
+					s := Speeds{
+						available: true,
+						Rx:        unit.Datarate(float64(counters.rx-prev.rx)/duration) * unit.BytePerSecond,
+						Tx:        unit.Datarate(float64(counters.tx-prev.tx)/duration) * unit.BytePerSecond,
+					}
+					bySpeed[iface] = s
+					totalRx += s.Rx
+					totalTx += s.Tx
+				}
+				last[iface] = counters
+			}
 
+			speeds = Speeds{
+				available:         len(bySpeed) > 0,
+				Rx:                totalRx,
+				Tx:                totalTx,
+				SpeedsByInterface: bySpeed,
+			}
 			lastRead = now
-			lastRx = rx
-			lastTx = tx
 		}
 	}
 }
 
 func linkRxTx(iface string) (rx, tx uint64, err error) {
-	var link netlink.Link
+	var link rtnetlink.Link
 	link, err = linkByName(iface)
 	if err != nil {
 		return
@@ -0,0 +1,183 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netspeed
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	rtnetlink "github.com/vishvananda/netlink"
+
+	"github.com/soumya92/barista/bar"
+	"github.com/soumya92/barista/base"
+	"github.com/soumya92/barista/base/watchers/netlink"
+)
+
+// fakeLink is a rtnetlink.Link backed by canned counters, so linkRxTx can be
+// exercised without a real netlink socket.
+type fakeLink struct {
+	attrs rtnetlink.LinkAttrs
+}
+
+func (f *fakeLink) Attrs() *rtnetlink.LinkAttrs { return &f.attrs }
+func (f *fakeLink) Type() string                { return "fake" }
+
+// fakeLinks is a swappable linkByName backed by a map of interface name to
+// counters, so a test can move an interface's counters around (including
+// simulating a different, lower-numbered interface reusing a name) without
+// touching the real netlink package.
+type fakeLinks struct {
+	mu      sync.Mutex
+	byIface map[string]ifaceCounters
+}
+
+func (f *fakeLinks) set(iface string, rx, tx uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.byIface == nil {
+		f.byIface = map[string]ifaceCounters{}
+	}
+	f.byIface[iface] = ifaceCounters{rx: rx, tx: tx}
+}
+
+func (f *fakeLinks) linkByName(iface string) (rtnetlink.Link, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.byIface[iface]
+	if !ok {
+		return nil, fmt.Errorf("fakeLinks: no counters set for %q", iface)
+	}
+	return &fakeLink{attrs: rtnetlink.LinkAttrs{
+		Statistics: &rtnetlink.LinkStatistics{RxBytes: c.rx, TxBytes: c.tx},
+	}}, nil
+}
+
+// waitFor polls check until it returns true, or fails the test after a
+// second -- the worker's output only reflects a netlink update a tick or two
+// later, so tests can't assert on it synchronously.
+func waitFor(t *testing.T, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if check() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition not met within 1s")
+}
+
+// testModule wires up a *module with a fake linkByName and a netlink
+// subscription the test controls directly, and captures every Speeds it
+// produces (rather than the bar.Output rendered from it) so assertions can
+// inspect per-interface counters.
+//
+// The module's worker loop runs for as long as the test process does --
+// Stream (and thus worker) has no stop mechanism, the same as in production.
+func testModule(t *testing.T) (updates netlink.Subscription, links *fakeLinks, history func() []Speeds) {
+	t.Helper()
+	origLinkByName := linkByName
+	t.Cleanup(func() { linkByName = origLinkByName })
+	links = &fakeLinks{}
+	linkByName = links.linkByName
+
+	updates = make(netlink.Subscription)
+	m := newModule(func() netlink.Subscription { return updates }).(*module)
+	m.RefreshInterval(5 * time.Millisecond)
+
+	var mu sync.Mutex
+	var seen []Speeds
+	m.OutputFunc(func(s Speeds) bar.Output {
+		mu.Lock()
+		seen = append(seen, s)
+		mu.Unlock()
+		return nil
+	})
+
+	ch := base.NewChannel()
+	go m.worker(ch)
+	go func() {
+		for range ch {
+		}
+	}()
+
+	return updates, links, func() []Speeds {
+		mu.Lock()
+		defer mu.Unlock()
+		out := make([]Speeds, len(seen))
+		copy(out, seen)
+		return out
+	}
+}
+
+func TestWorkerComputesSpeedForPresentInterface(t *testing.T) {
+	updates, links, history := testModule(t)
+
+	links.set("eth0", 1000, 500)
+	updates <- netlink.Update{Name: "eth0", State: netlink.Up}
+
+	waitFor(t, func() bool {
+		for _, s := range history() {
+			if _, ok := s.SpeedsByInterface["eth0"]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestWorkerDiscardsCountersWhenInterfaceDisappears(t *testing.T) {
+	updates, links, history := testModule(t)
+
+	// eth0 appears with a large byte count...
+	links.set("eth0", 1_000_000, 500_000)
+	updates <- netlink.Update{Name: "eth0", State: netlink.Up}
+	waitFor(t, func() bool {
+		for _, s := range history() {
+			if _, ok := s.SpeedsByInterface["eth0"]; ok {
+				return true
+			}
+		}
+		return false
+	})
+
+	// ...then disappears, and a different link reuses the name "eth0" with
+	// a much smaller byte count, the way a freshly-(re)created interface
+	// would. If its last-known counters weren't discarded on disappearance,
+	// the next delta would be computed against the old, much larger
+	// counters, underflowing the unsigned subtraction into a huge bogus
+	// speed instead of either nothing (first tick) or a small real one.
+	updates <- netlink.Update{Name: "eth0", State: netlink.NotPresent}
+	links.set("eth0", 10, 5)
+	updates <- netlink.Update{Name: "eth0", State: netlink.Up}
+
+	waitFor(t, func() bool {
+		for _, s := range history() {
+			if sp, ok := s.SpeedsByInterface["eth0"]; ok && sp.Rx+sp.Tx < 1e6 {
+				return true
+			}
+		}
+		return false
+	})
+
+	for _, s := range history() {
+		if sp, ok := s.SpeedsByInterface["eth0"]; ok && sp.Total() > 1e6 {
+			t.Fatalf("got bogus speed %+v after eth0 reappeared with smaller counters; "+
+				"last-known counters were not discarded on disappearance", sp)
+		}
+	}
+}
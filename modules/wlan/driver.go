@@ -0,0 +1,152 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wlan
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/soumya92/barista/modules/wlan/internal/nl80211"
+)
+
+// driver fills in the fields of Info that aren't available from the netlink
+// link-state watcher alone. It's an interface so tests can substitute a
+// fake transport instead of talking to a real wireless card.
+type driver interface {
+	fillWifiInfo(info *Info) error
+}
+
+// netlinkDriver is the real driver, backed by a kernel nl80211 connection.
+// Module.stopRetry/startRetry can restart the base.Retryable driving
+// fillWifiInfo (e.g. on a netlink link-state change such as roaming) while
+// a previous call is still blocked inside a netlink syscall on the same
+// client/socket; mu serialises fillWifiInfo so two calls never use the
+// connection concurrently.
+type netlinkDriver struct {
+	mu     sync.Mutex
+	client *nl80211.Client
+}
+
+func newNetlinkDriver() (driver, error) {
+	client, err := nl80211.New()
+	if err != nil {
+		return nil, err
+	}
+	return &netlinkDriver{client: client}, nil
+}
+
+func (d *netlinkDriver) fillWifiInfo(info *Info) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ifindex, err := interfaceIndex(info.Name)
+	if err != nil {
+		return err
+	}
+	iface, err := d.client.GetInterface(ifindex)
+	if err != nil {
+		return err
+	}
+	info.SSID = iface.SSID
+	info.Frequency = float64(iface.FreqMHz)
+	info.Channel = channelForFrequency(iface.FreqMHz)
+	info.TxPower = iface.TxPowerDBM
+
+	if noise, err := d.client.GetNoise(ifindex); err == nil {
+		info.NoiseDBM = noise
+	}
+
+	bss, err := d.client.GetConnectedBSS(ifindex)
+	if err != nil {
+		// Not an error for the fields filled in above. Without the
+		// connected BSS there is no BSSID to query the station with, so
+		// signal and bitrate are left unset as well as security.
+		bss = nl80211.BSS{}
+	} else {
+		info.AccessPointMAC = bss.BSSID.String()
+		info.Security = securityFromIEs(bss.InformationElements)
+	}
+
+	if bss.BSSID == nil {
+		return nil
+	}
+	sta, err := d.client.GetStation(ifindex, bss.BSSID)
+	if err != nil {
+		return nil
+	}
+	info.SignalDBM = sta.SignalDBM
+	info.SignalPercent = signalPercent(sta.SignalDBM)
+	info.BitrateMbps = sta.TxBitrate.BitrateMbps
+	return nil
+}
+
+// interfaceIndex resolves a netdev name to the ifindex nl80211 expects.
+// Overridable for tests.
+var interfaceIndex = func(name string) (int, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return iface.Index, nil
+}
+
+// signalPercent converts an RSSI in dBm to the 0-100 scale most desktop
+// environments show, using the same -50/-100 dBm range as NetworkManager.
+func signalPercent(dbm int) int {
+	switch {
+	case dbm >= -50:
+		return 100
+	case dbm <= -100:
+		return 0
+	default:
+		return 2 * (dbm + 100)
+	}
+}
+
+// channelForFrequency converts a center frequency in MHz to an 802.11
+// channel number, covering the 2.4GHz and 5GHz bands.
+func channelForFrequency(freqMHz int) int {
+	switch {
+	case freqMHz == 2484:
+		return 14
+	case freqMHz >= 2412 && freqMHz <= 2472:
+		return (freqMHz-2412)/5 + 1
+	case freqMHz >= 5160 && freqMHz <= 5885:
+		return (freqMHz-5000)/5
+	default:
+		return 0
+	}
+}
+
+// iwgetidDriver is the legacy fallback, used when nl80211 isn't available
+// (e.g. running in a container without cfg80211, or on a kernel too old to
+// support the commands this package issues).
+type iwgetidDriver struct{}
+
+func (iwgetidDriver) fillWifiInfo(info *Info) error {
+	ssid, err := iwgetid(info.Name, "-r")
+	if err != nil {
+		return err
+	}
+	info.SSID = ssid
+	info.AccessPointMAC, _ = iwgetid(info.Name, "-a")
+	ch, _ := iwgetid(info.Name, "-c")
+	info.Channel, _ = strconv.Atoi(ch)
+	freq, _ := iwgetid(info.Name, "-f")
+	info.Frequency, _ = strconv.ParseFloat(freq, 64)
+	info.Security = SecurityUnknown
+	return nil
+}
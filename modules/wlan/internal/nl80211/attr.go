@@ -0,0 +1,85 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nl80211
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Attr is a single netlink type-length-value attribute, as used by both the
+// outer genetlink payload and every nested attribute list within it.
+type Attr struct {
+	Type uint16
+	Data []byte
+}
+
+func nlaAlign(n int) int {
+	return (n + 3) &^ 3
+}
+
+// u16Attr/u32Attr encode fixed-width little-endian attributes, matching the
+// host byte order netlink always uses on Linux.
+func u16Attr(typ uint16, v uint16) Attr {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return Attr{Type: typ, Data: b}
+}
+
+func u32Attr(typ uint16, v uint32) Attr {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return Attr{Type: typ, Data: b}
+}
+
+// encodeAttrs serialises a list of attributes into the wire format expected
+// as a genetlink message payload: each attribute is a 4-byte length+type
+// header followed by its value, padded to a 4-byte boundary.
+func encodeAttrs(attrs []Attr) []byte {
+	var out []byte
+	for _, a := range attrs {
+		hdr := make([]byte, 4)
+		binary.LittleEndian.PutUint16(hdr, uint16(4+len(a.Data)))
+		binary.LittleEndian.PutUint16(hdr[2:], a.Type)
+		out = append(out, hdr...)
+		out = append(out, a.Data...)
+		if pad := nlaAlign(len(a.Data)) - len(a.Data); pad > 0 {
+			out = append(out, make([]byte, pad)...)
+		}
+	}
+	return out
+}
+
+// parseAttrs walks a flat (possibly nested) nlattr TLV list and returns the
+// last value seen for each attribute type, mirroring how the kernel allows
+// repeated attributes but callers generally only care about one.
+func parseAttrs(b []byte) (map[uint16][]byte, error) {
+	attrs := make(map[uint16][]byte)
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("nl80211: truncated attribute header")
+		}
+		length := int(binary.LittleEndian.Uint16(b))
+		typ := binary.LittleEndian.Uint16(b[2:])
+		if length < 4 || length > len(b) {
+			return nil, fmt.Errorf("nl80211: attribute %d has invalid length %d", typ, length)
+		}
+		// Clear the two high bits (NLA_F_NESTED, NLA_F_NET_BYTEORDER), which
+		// are flags rather than part of the type.
+		attrs[typ&0x3fff] = b[4:length]
+		b = b[nlaAlign(length):]
+	}
+	return attrs, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nl80211
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeParseAttrsRoundTrip(t *testing.T) {
+	attrs := []Attr{
+		u32Attr(attrIfindex, 3),
+		{Type: attrSSID, Data: []byte("test wifi")}, // odd length, needs padding
+		u16Attr(attrGeneration, 7),
+	}
+	parsed, err := parseAttrs(encodeAttrs(attrs))
+	if err != nil {
+		t.Fatalf("parseAttrs: %v", err)
+	}
+	if len(parsed) != len(attrs) {
+		t.Fatalf("got %d attrs, want %d", len(parsed), len(attrs))
+	}
+	for _, a := range attrs {
+		got, ok := parsed[a.Type]
+		if !ok {
+			t.Errorf("attr %d missing from parsed result", a.Type)
+			continue
+		}
+		if !bytes.Equal(got, a.Data) {
+			t.Errorf("attr %d = %v, want %v", a.Type, got, a.Data)
+		}
+	}
+}
+
+func TestParseAttrsClearsFlagBits(t *testing.T) {
+	// NLA_F_NESTED | NLA_F_NET_BYTEORDER set on top of a real attribute type.
+	const flagged = attrSSID | 0x8000 | 0x4000
+	raw := encodeAttrs([]Attr{{Type: flagged, Data: []byte("x")}})
+	parsed, err := parseAttrs(raw)
+	if err != nil {
+		t.Fatalf("parseAttrs: %v", err)
+	}
+	if _, ok := parsed[attrSSID]; !ok {
+		t.Fatalf("parseAttrs did not strip flag bits from attribute type: %v", parsed)
+	}
+}
+
+func TestParseAttrsTruncated(t *testing.T) {
+	if _, err := parseAttrs([]byte{1, 2}); err == nil {
+		t.Fatal("parseAttrs on a truncated header should return an error")
+	}
+}
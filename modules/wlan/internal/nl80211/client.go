@@ -0,0 +1,230 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nl80211 implements a small client for the kernel's nl80211
+// generic-netlink family, sufficient to read the wireless info the wlan
+// module needs (SSID/BSSID/frequency, signal/bitrate for the current
+// station, and the information elements of the connected BSS) without
+// shelling out to iwgetid.
+package nl80211
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Client queries nl80211 over a generic-netlink connection.
+type Client struct {
+	conn   Conn
+	family uint16
+}
+
+// New dials a genetlink socket and resolves the nl80211 family id. It
+// returns ErrNotAvailable if the family isn't registered, so callers can
+// fall back to another information source.
+func New() (*Client, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	return newClient(conn)
+}
+
+func newClient(conn Conn) (*Client, error) {
+	family, err := resolveFamily(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{conn: conn, family: family}, nil
+}
+
+// Close releases the underlying netlink socket.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Interface describes the state nl80211 reports for a wireless netdev.
+type Interface struct {
+	SSID       string
+	FreqMHz    int
+	TxPowerDBM float64
+}
+
+// GetInterface issues NL80211_CMD_GET_INTERFACE for the given ifindex.
+func (c *Client) GetInterface(ifindex int) (Interface, error) {
+	msgs, err := c.conn.Execute(c.family, cmdGetInterface, false, []Attr{
+		u32Attr(attrIfindex, uint32(ifindex)),
+	})
+	if err != nil {
+		return Interface{}, fmt.Errorf("nl80211: get interface: %w", err)
+	}
+	if len(msgs) == 0 {
+		return Interface{}, fmt.Errorf("nl80211: no response for ifindex %d", ifindex)
+	}
+	attrs, err := parseAttrs(msgs[0])
+	if err != nil {
+		return Interface{}, err
+	}
+	var info Interface
+	if ssid, ok := attrs[attrSSID]; ok {
+		info.SSID = string(ssid)
+	}
+	if freq, ok := attrs[attrWiphyFreq]; ok && len(freq) >= 4 {
+		info.FreqMHz = int(binary.LittleEndian.Uint32(freq))
+	}
+	if txp, ok := attrs[attrWiphyTXPowerLevel]; ok && len(txp) >= 4 {
+		// Reported in mBm (1/100 dBm).
+		info.TxPowerDBM = float64(int32(binary.LittleEndian.Uint32(txp))) / 100
+	}
+	return info, nil
+}
+
+// RateInfo describes the bitrate of a station link.
+type RateInfo struct {
+	BitrateMbps float64
+}
+
+// Station describes the link-layer state of a station, as reported for the
+// BSSID we're associated with when queried from a client interface. Noise
+// isn't a station-level attribute in nl80211 (it's a property of the
+// channel, not the peer); see GetNoise.
+type Station struct {
+	SignalDBM int
+	TxBitrate RateInfo
+}
+
+// GetStation issues NL80211_CMD_GET_STATION for the given ifindex/BSSID.
+func (c *Client) GetStation(ifindex int, bssid net.HardwareAddr) (Station, error) {
+	msgs, err := c.conn.Execute(c.family, cmdGetStation, false, []Attr{
+		u32Attr(attrIfindex, uint32(ifindex)),
+		{Type: attrMAC, Data: []byte(bssid)},
+	})
+	if err != nil {
+		return Station{}, fmt.Errorf("nl80211: get station: %w", err)
+	}
+	if len(msgs) == 0 {
+		return Station{}, fmt.Errorf("nl80211: no station info for %s", bssid)
+	}
+	attrs, err := parseAttrs(msgs[0])
+	if err != nil {
+		return Station{}, err
+	}
+	var sta Station
+	if info, ok := attrs[attrStaInfo]; ok {
+		nested, err := parseAttrs(info)
+		if err != nil {
+			return Station{}, err
+		}
+		if sig, ok := nested[staInfoSignal]; ok && len(sig) >= 1 {
+			sta.SignalDBM = int(int8(sig[0]))
+		}
+		if rate, ok := nested[staInfoTxBitrate]; ok {
+			sta.TxBitrate = parseRateInfo(rate)
+		}
+	}
+	return sta, nil
+}
+
+func parseRateInfo(b []byte) RateInfo {
+	nested, err := parseAttrs(b)
+	if err != nil {
+		return RateInfo{}
+	}
+	// Prefer the uncapped 32-bit field; the 16-bit one tops out at 65535
+	// (~6.5Gbit/s in 100kbit/s units) and is only used as a fallback.
+	if v, ok := nested[rateInfoBitrate32]; ok && len(v) >= 4 {
+		return RateInfo{BitrateMbps: float64(binary.LittleEndian.Uint32(v)) / 10}
+	}
+	if v, ok := nested[rateInfoBitrate]; ok && len(v) >= 2 {
+		return RateInfo{BitrateMbps: float64(binary.LittleEndian.Uint16(v)) / 10}
+	}
+	return RateInfo{}
+}
+
+// BSS describes a scanned basic service set.
+type BSS struct {
+	BSSID               net.HardwareAddr
+	InformationElements []byte
+}
+
+// GetConnectedBSS issues NL80211_CMD_GET_SCAN for the given ifindex and
+// returns the entry marked as the one we're currently associated with, so
+// its information elements can be inspected for RSN/WPA security info.
+func (c *Client) GetConnectedBSS(ifindex int) (BSS, error) {
+	msgs, err := c.conn.Execute(c.family, cmdGetScan, true, []Attr{
+		u32Attr(attrIfindex, uint32(ifindex)),
+	})
+	if err != nil {
+		return BSS{}, fmt.Errorf("nl80211: get scan: %w", err)
+	}
+	for _, m := range msgs {
+		attrs, err := parseAttrs(m)
+		if err != nil {
+			continue
+		}
+		bssAttr, ok := attrs[attrBSS]
+		if !ok {
+			continue
+		}
+		nested, err := parseAttrs(bssAttr)
+		if err != nil {
+			continue
+		}
+		status, ok := nested[bssStatus]
+		if !ok || len(status) < 4 || binary.LittleEndian.Uint32(status) != bssStatusAssociated {
+			continue
+		}
+		bss := BSS{InformationElements: nested[bssInformationElements]}
+		if mac, ok := nested[bssBSSID]; ok {
+			bss.BSSID = net.HardwareAddr(mac)
+		}
+		return bss, nil
+	}
+	return BSS{}, fmt.Errorf("nl80211: no associated BSS for ifindex %d", ifindex)
+}
+
+// GetNoise issues NL80211_CMD_GET_SURVEY for the given ifindex and returns
+// the noise floor, in dBm, of whichever surveyed channel is marked as the
+// one currently in use.
+func (c *Client) GetNoise(ifindex int) (int, error) {
+	msgs, err := c.conn.Execute(c.family, cmdGetSurvey, true, []Attr{
+		u32Attr(attrIfindex, uint32(ifindex)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("nl80211: get survey: %w", err)
+	}
+	for _, m := range msgs {
+		attrs, err := parseAttrs(m)
+		if err != nil {
+			continue
+		}
+		surveyAttr, ok := attrs[attrSurveyInfo]
+		if !ok {
+			continue
+		}
+		nested, err := parseAttrs(surveyAttr)
+		if err != nil {
+			continue
+		}
+		if _, ok := nested[surveyInfoInUse]; !ok {
+			continue
+		}
+		if noise, ok := nested[surveyInfoNoise]; ok && len(noise) >= 1 {
+			return int(int8(noise[0])), nil
+		}
+	}
+	return 0, fmt.Errorf("nl80211: no in-use channel survey for ifindex %d", ifindex)
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nl80211
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrNotAvailable is returned by New when the running kernel has no nl80211
+// support (cfg80211 not loaded, or no generic-netlink at all).
+var ErrNotAvailable = errors.New("nl80211: family not available")
+
+// Conn is the netlink transport used by Client. The real implementation
+// talks to an AF_NETLINK/NETLINK_GENERIC socket; tests substitute a fake
+// that plays back canned responses without touching the kernel.
+type Conn interface {
+	// Execute sends a single genetlink request for the given family id and
+	// command, with the given attributes as payload, and returns the
+	// payload of every reply message (with genlmsghdr stripped), handling
+	// multipart NLM_F_DUMP responses transparently.
+	Execute(familyID uint16, cmd uint8, dump bool, attrs []Attr) ([][]byte, error)
+	Close() error
+}
+
+// rawConn is the raw datagram transport underneath sockConn: one Send per
+// request, and as many Reads as it takes to see the response through to its
+// terminating ack/NLMSG_DONE. Pulled out of sockConn so tests can exercise
+// Execute's framing and ack-draining logic against a fake that plays back
+// canned reads without a real netlink socket.
+type rawConn interface {
+	Send(b []byte) error
+	Read(b []byte) (int, error)
+	Close() error
+}
+
+// fdConn is the production rawConn, backed by a real AF_NETLINK socket fd.
+type fdConn int
+
+func (c fdConn) Send(b []byte) error {
+	return unix.Send(int(c), b, 0)
+}
+
+func (c fdConn) Read(b []byte) (int, error) {
+	return unix.Read(int(c), b)
+}
+
+func (c fdConn) Close() error {
+	return unix.Close(int(c))
+}
+
+// sockConn is the production Conn, built on top of a rawConn.
+type sockConn struct {
+	raw rawConn
+	seq uint32
+}
+
+func dial() (*sockConn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, fmt.Errorf("nl80211: socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("nl80211: bind: %w", err)
+	}
+	return &sockConn{raw: fdConn(fd)}, nil
+}
+
+func (c *sockConn) Close() error {
+	return c.raw.Close()
+}
+
+func (c *sockConn) nextSeq() uint32 {
+	return atomic.AddUint32(&c.seq, 1)
+}
+
+const (
+	nlmsghdrLen   = 16
+	genlmsghdrLen = 4
+)
+
+func (c *sockConn) Execute(familyID uint16, cmd uint8, dump bool, attrs []Attr) ([][]byte, error) {
+	seq := c.nextSeq()
+	payload := append([]byte{cmd, 1 /* version */, 0, 0}, encodeAttrs(attrs)...)
+
+	// Every request sets NLM_F_ACK, so a non-dump command's response is two
+	// distinct datagrams: the data reply and a separate NLMSG_ERROR ack.
+	// consumeFrames keeps reading until it actually sees that ack (a dump
+	// instead reads until NLMSG_DONE, which implies it), rather than
+	// returning after the first read and leaving the ack to be misread by
+	// the next Execute call.
+	flags := uint16(unix.NLM_F_REQUEST | unix.NLM_F_ACK)
+	if dump {
+		flags |= unix.NLM_F_DUMP
+	}
+	msg := make([]byte, nlmsghdrLen)
+	binary.LittleEndian.PutUint32(msg, uint32(nlmsghdrLen+len(payload)))
+	binary.LittleEndian.PutUint16(msg[4:], familyID)
+	binary.LittleEndian.PutUint16(msg[6:], flags)
+	binary.LittleEndian.PutUint32(msg[8:], seq)
+	// msg[12:16] (pid) left zero; the kernel fills in the sender's port id.
+	msg = append(msg, payload...)
+
+	if err := c.raw.Send(msg); err != nil {
+		return nil, fmt.Errorf("nl80211: send: %w", err)
+	}
+
+	var results [][]byte
+	buf := make([]byte, 1<<16)
+	for {
+		n, err := c.raw.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("nl80211: read: %w", err)
+		}
+		done, err := consumeFrames(buf[:n], seq, dump, &results)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return results, nil
+		}
+	}
+}
+
+// consumeFrames parses every netlink message in b, appending the genetlink
+// payload of each data frame to *results, and reports whether the response
+// is now complete: for a dump, that's NLMSG_DONE; for a single request,
+// that's the NLMSG_ERROR ack. Frames whose sequence number doesn't match
+// seq are a stray reply left over from some earlier, already-finished
+// request (e.g. the previous call's ack, read a moment too late) and are
+// skipped rather than treated as part of this response.
+func consumeFrames(b []byte, seq uint32, dump bool, results *[][]byte) (done bool, err error) {
+	for len(b) >= nlmsghdrLen {
+		length := int(binary.LittleEndian.Uint32(b))
+		typ := binary.LittleEndian.Uint16(b[4:])
+		msgSeq := binary.LittleEndian.Uint32(b[8:])
+		if length < nlmsghdrLen || length > len(b) {
+			return false, fmt.Errorf("nl80211: truncated netlink message")
+		}
+		body := b[nlmsghdrLen:length]
+		if msgSeq != seq {
+			b = b[nlmsgAlign(length):]
+			continue
+		}
+		switch typ {
+		case unix.NLMSG_ERROR:
+			if errno := int32(binary.LittleEndian.Uint32(body)); errno != 0 {
+				return false, fmt.Errorf("nl80211: netlink error %d", -errno)
+			}
+			if !dump {
+				done = true
+			}
+		case unix.NLMSG_DONE:
+			done = true
+		default:
+			if len(body) >= genlmsghdrLen {
+				*results = append(*results, body[genlmsghdrLen:])
+			}
+		}
+		b = b[nlmsgAlign(length):]
+	}
+	return done, nil
+}
+
+func nlmsgAlign(n int) int {
+	return (n + 3) &^ 3
+}
+
+// resolveFamily issues CTRL_CMD_GETFAMILY against the fixed-id control
+// family to find nl80211's dynamically allocated family id.
+func resolveFamily(c Conn) (uint16, error) {
+	msgs, err := c.Execute(genlIDCtrl, ctrlCmdGetFamily, false, []Attr{
+		{Type: ctrlAttrFamilyName, Data: append([]byte(familyName), 0)},
+	})
+	if err != nil {
+		return 0, ErrNotAvailable
+	}
+	for _, m := range msgs {
+		attrs, err := parseAttrs(m)
+		if err != nil {
+			continue
+		}
+		if id, ok := attrs[ctrlAttrFamilyID]; ok && len(id) >= 2 {
+			return binary.LittleEndian.Uint16(id), nil
+		}
+	}
+	return 0, ErrNotAvailable
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nl80211
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// fakeRawConn plays back a fixed sequence of Read results, recording every
+// Send, so Execute's framing/ack-draining logic can be tested without a
+// real netlink socket.
+type fakeRawConn struct {
+	sent  [][]byte
+	reads [][]byte
+	next  int
+}
+
+func (f *fakeRawConn) Send(b []byte) error {
+	f.sent = append(f.sent, append([]byte(nil), b...))
+	return nil
+}
+
+func (f *fakeRawConn) Read(b []byte) (int, error) {
+	if f.next >= len(f.reads) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.reads[f.next])
+	f.next++
+	return n, nil
+}
+
+func (f *fakeRawConn) Close() error { return nil }
+
+func nlHeader(length int, typ uint16, seq uint32) []byte {
+	h := make([]byte, nlmsghdrLen)
+	binary.LittleEndian.PutUint32(h, uint32(length))
+	binary.LittleEndian.PutUint16(h[4:], typ)
+	binary.LittleEndian.PutUint32(h[8:], seq)
+	return h
+}
+
+// dataMsg builds a netlink message carrying a genlmsghdr + payload body, as
+// if it were a normal (non-error, non-done) reply.
+func dataMsg(seq uint32, payload []byte) []byte {
+	body := append([]byte{0, 0, 0, 0}, payload...) // cmd, version, 2 bytes pad
+	msg := append(nlHeader(nlmsghdrLen+len(body), 0x10, seq), body...)
+	for len(msg)%4 != 0 {
+		msg = append(msg, 0)
+	}
+	return msg
+}
+
+func ackMsg(seq uint32, errno int32) []byte {
+	body := make([]byte, 4)
+	binary.LittleEndian.PutUint32(body, uint32(errno))
+	return append(nlHeader(nlmsghdrLen+len(body), unix.NLMSG_ERROR, seq), body...)
+}
+
+func doneMsg(seq uint32) []byte {
+	return nlHeader(nlmsghdrLen, unix.NLMSG_DONE, seq)
+}
+
+func TestExecuteNonDumpDrainsTrailingAck(t *testing.T) {
+	raw := &fakeRawConn{reads: [][]byte{
+		dataMsg(1, []byte("payload")),
+		ackMsg(1, 0),
+	}}
+	c := &sockConn{raw: raw}
+	msgs, err := c.Execute(0x10, 5, false, nil)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(msgs) != 1 || !bytes.Equal(msgs[0], []byte("payload")) {
+		t.Fatalf("Execute returned %v, want one message with %q", msgs, "payload")
+	}
+	// Regression check: the ack must actually be consumed by this call (two
+	// reads), not left on the socket for the next Execute to stumble on.
+	if raw.next != 2 {
+		t.Fatalf("Execute consumed %d reads, want 2 (data + ack)", raw.next)
+	}
+}
+
+func TestExecuteIgnoresStraySequenceNumber(t *testing.T) {
+	// A leftover reply from some earlier, already-finished request (seq 99)
+	// arrives interleaved with this request's own data+ack (seq 1); it must
+	// not be mistaken for part of this response.
+	raw := &fakeRawConn{reads: [][]byte{
+		append(dataMsg(99, []byte("stale")), dataMsg(1, []byte("fresh"))...),
+		ackMsg(1, 0),
+	}}
+	c := &sockConn{raw: raw}
+	msgs, err := c.Execute(0x10, 5, false, nil)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(msgs) != 1 || !bytes.Equal(msgs[0], []byte("fresh")) {
+		t.Fatalf("Execute returned %v, want only the seq-matching message %q", msgs, "fresh")
+	}
+}
+
+func TestExecuteDumpReadsUntilDone(t *testing.T) {
+	raw := &fakeRawConn{reads: [][]byte{
+		dataMsg(1, []byte("one")),
+		append(dataMsg(1, []byte("two")), doneMsg(1)...),
+	}}
+	c := &sockConn{raw: raw}
+	msgs, err := c.Execute(0x10, 32, true, nil)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(msgs) != 2 || !bytes.Equal(msgs[0], []byte("one")) || !bytes.Equal(msgs[1], []byte("two")) {
+		t.Fatalf("Execute returned %v, want [one two]", msgs)
+	}
+}
+
+func TestExecutePropagatesNetlinkError(t *testing.T) {
+	raw := &fakeRawConn{reads: [][]byte{ackMsg(1, -2)}} // -ENOENT
+	c := &sockConn{raw: raw}
+	if _, err := c.Execute(0x10, 5, false, nil); err == nil {
+		t.Fatal("Execute should have returned an error for a non-zero netlink ack")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nl80211
+
+// Generic netlink control family, always registered at a fixed id.
+const (
+	genlIDCtrl       = 0x10
+	ctrlCmdGetFamily = 3
+
+	ctrlAttrFamilyID   = 1
+	ctrlAttrFamilyName = 2
+)
+
+// familyName is the name nl80211 registers itself under with genetlink.
+const familyName = "nl80211"
+
+// nl80211 commands, from linux/nl80211.h. Only the ones this package issues
+// or expects back are listed.
+const (
+	cmdGetInterface  = 5
+	cmdGetScan       = 32
+	cmdNewScanResult = 34
+	cmdGetStation    = 17
+	cmdGetSurvey     = 50
+)
+
+// nl80211 attributes, from linux/nl80211.h. Only the ones this package reads
+// or writes are listed.
+const (
+	attrIfindex           = 3
+	attrMAC               = 6
+	attrWiphyFreq         = 38
+	attrWiphyTXPowerLevel = 62
+	attrSSID              = 52
+	attrStaInfo           = 21
+	attrBSS               = 47
+	attrGeneration        = 46
+	attrSurveyInfo        = 45
+)
+
+// NL80211_SURVEY_INFO_* attributes, nested inside attrSurveyInfo.
+const (
+	surveyInfoFrequency = 1
+	surveyInfoNoise     = 2
+	surveyInfoInUse     = 4
+)
+
+// NL80211_STA_INFO_* attributes, nested inside attrStaInfo.
+const (
+	staInfoSignal    = 7
+	staInfoTxBitrate = 8
+	staInfoSignalAvg = 20
+)
+
+// NL80211_RATE_INFO_* attributes, nested inside staInfoTxBitrate.
+const (
+	rateInfoBitrate   = 1 // 100 kbit/s units, capped at 65535
+	rateInfoBitrate32 = 5 // 100 kbit/s units, uncapped
+)
+
+// NL80211_BSS_* attributes, nested inside attrBSS.
+const (
+	bssBSSID               = 1
+	bssFrequency           = 2
+	bssInformationElements = 6
+	bssStatus              = 9
+)
+
+// bssStatusAssociated is the value of bssStatus for the BSS we're currently
+// associated with.
+const bssStatusAssociated = 1
@@ -0,0 +1,156 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wlan
+
+// Security represents the authentication/encryption scheme in use on a
+// wireless network.
+type Security int
+
+const (
+	// SecurityUnknown means the security type could not be determined,
+	// e.g. because the backend in use can't report information elements.
+	SecurityUnknown Security = iota
+	// SecurityOpen means the network has no authentication or encryption.
+	SecurityOpen
+	// SecurityWEP means the network uses (broken, legacy) WEP.
+	SecurityWEP
+	// SecurityWPA means the network negotiated WPA (TKIP, not WPA2/3).
+	SecurityWPA
+	// SecurityWPA2 means the network negotiated WPA2 (RSN, pre-shared key).
+	SecurityWPA2
+	// SecurityWPA3 means the network negotiated WPA3 (RSN, SAE).
+	SecurityWPA3
+	// SecurityEnterprise means the network uses 802.1X/EAP authentication,
+	// as layered on top of WPA/WPA2/WPA3 in an enterprise deployment.
+	SecurityEnterprise
+)
+
+// String returns a human-readable name for the security type.
+func (s Security) String() string {
+	switch s {
+	case SecurityOpen:
+		return "Open"
+	case SecurityWEP:
+		return "WEP"
+	case SecurityWPA:
+		return "WPA"
+	case SecurityWPA2:
+		return "WPA2"
+	case SecurityWPA3:
+		return "WPA3"
+	case SecurityEnterprise:
+		return "802.1X"
+	default:
+		return "Unknown"
+	}
+}
+
+// Information element IDs, from the 802.11 spec. They live here rather than
+// in internal/nl80211 because they describe 802.11 frame contents, not
+// anything specific to the nl80211 netlink API.
+const (
+	ieRSN    = 48
+	ieVendor = 221
+)
+
+// 802.11 AKM (authentication key management) suite selectors, as found in
+// the RSN information element. The first 3 bytes are the OUI (00-0f-ac for
+// the standard IEEE suites); the last byte selects the specific AKM.
+const (
+	akmPSK    = 2  // WPA2-Personal
+	akmSAE    = 8  // WPA3-Personal (SAE)
+	akm8021X  = 1  // WPA2-Enterprise
+	akm8021X3 = 12 // WPA3-Enterprise (802.1X-SHA-256 variant with PMF)
+)
+
+// securityFromIEs inspects the information elements of a scanned/associated
+// BSS and derives the security type barista displays. It looks for an RSN
+// element (WPA2/WPA3/enterprise) and falls back to the vendor-specific WPA1
+// element before concluding the network is open.
+func securityFromIEs(ies []byte) Security {
+	if len(ies) == 0 {
+		return SecurityUnknown
+	}
+	enterprise := false
+	sawWPA3 := false
+	sawWPA2 := false
+	sawWPA1 := false
+	for len(ies) >= 2 {
+		id, length := ies[0], int(ies[1])
+		if length+2 > len(ies) {
+			break
+		}
+		body := ies[2 : 2+length]
+		switch {
+		case id == ieRSN:
+			sawWPA2 = true
+			for _, akm := range rsnAKMs(body) {
+				switch akm {
+				case akmSAE:
+					sawWPA3 = true
+				case akm8021X, akm8021X3:
+					enterprise = true
+				}
+			}
+		case id == ieVendor && isWPA1Vendor(body):
+			sawWPA1 = true
+		}
+		ies = ies[2+length:]
+	}
+	switch {
+	case enterprise:
+		return SecurityEnterprise
+	case sawWPA3:
+		return SecurityWPA3
+	case sawWPA2:
+		return SecurityWPA2
+	case sawWPA1:
+		return SecurityWPA
+	default:
+		return SecurityOpen
+	}
+}
+
+// wpaOUI is the Microsoft OUI used by the pre-standard WPA1 vendor IE.
+var wpaOUI = [3]byte{0x00, 0x50, 0xf2}
+
+func isWPA1Vendor(body []byte) bool {
+	return len(body) >= 4 && body[0] == wpaOUI[0] && body[1] == wpaOUI[1] &&
+		body[2] == wpaOUI[2] && body[3] == 0x01
+}
+
+// rsnAKMs extracts the low byte of each AKM suite selector from the body of
+// an RSN information element, skipping over the fixed-size version, group
+// cipher and pairwise cipher list to reach the AKM list.
+func rsnAKMs(body []byte) []byte {
+	// version(2) + group cipher(4) + pairwise count(2) + pairwise ciphers(4 each)
+	if len(body) < 8 {
+		return nil
+	}
+	pos := 8
+	pairwiseCount := int(body[6]) | int(body[7])<<8
+	pos = 8 + pairwiseCount*4
+	if pos+2 > len(body) {
+		return nil
+	}
+	akmCount := int(body[pos]) | int(body[pos+1])<<8
+	pos += 2
+	var akms []byte
+	for i := 0; i < akmCount && pos+4 <= len(body); i++ {
+		akms = append(akms, body[pos+3])
+		pos += 4
+	}
+	return akms
+}
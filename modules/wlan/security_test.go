@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wlan
+
+import "testing"
+
+// rsnIE builds a minimal RSN information element with no pairwise ciphers
+// and a single AKM suite selector.
+func rsnIE(akm byte) []byte {
+	body := []byte{
+		1, 0, // version
+		0, 0, 0, 0, // group cipher
+		0, 0, // pairwise cipher count (0)
+		1, 0, // AKM count (1)
+		0, 0x0f, 0xac, akm, // OUI + suite type
+	}
+	return append([]byte{ieRSN, byte(len(body))}, body...)
+}
+
+func vendorWPA1IE() []byte {
+	body := append([]byte{0x00, 0x50, 0xf2, 0x01}, 0, 0)
+	return append([]byte{ieVendor, byte(len(body))}, body...)
+}
+
+func TestSecurityFromIEs(t *testing.T) {
+	cases := []struct {
+		name string
+		ies  []byte
+		want Security
+	}{
+		{"empty", nil, SecurityUnknown},
+		{"open", []byte{1, 0}, SecurityOpen}, // unrelated IE only
+		{"wpa2-psk", rsnIE(akmPSK), SecurityWPA2},
+		{"wpa3-sae", rsnIE(akmSAE), SecurityWPA3},
+		{"enterprise", rsnIE(akm8021X), SecurityEnterprise},
+		{"wpa1-vendor", vendorWPA1IE(), SecurityWPA},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := securityFromIEs(c.ies); got != c.want {
+				t.Errorf("securityFromIEs(%v) = %v, want %v", c.ies, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSignalPercent(t *testing.T) {
+	cases := []struct {
+		dbm  int
+		want int
+	}{
+		{-40, 100},
+		{-50, 100},
+		{-75, 50},
+		{-100, 0},
+		{-120, 0},
+	}
+	for _, c := range cases {
+		if got := signalPercent(c.dbm); got != c.want {
+			t.Errorf("signalPercent(%d) = %d, want %d", c.dbm, got, c.want)
+		}
+	}
+}
+
+func TestChannelForFrequency(t *testing.T) {
+	cases := []struct {
+		freq int
+		want int
+	}{
+		{2412, 1},
+		{2437, 6},
+		{2484, 14},
+		{5180, 36},
+		{0, 0},
+	}
+	for _, c := range cases {
+		if got := channelForFrequency(c.freq); got != c.want {
+			t.Errorf("channelForFrequency(%d) = %d, want %d", c.freq, got, c.want)
+		}
+	}
+}
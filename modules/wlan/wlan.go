@@ -13,15 +13,17 @@
 // limitations under the License.
 
 // Package wlan provides an i3bar module for wireless information.
-// NOTE: This module REQUIRES the external command "iwgetid",
-// because getting the SSID is a privileged operation.
+// It talks to the kernel's nl80211 generic-netlink family directly, and
+// only falls back to shelling out to the external "iwgetid" command on
+// kernels where nl80211 isn't available.
 package wlan
 
 import (
 	"net"
 	"os/exec"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/soumya92/barista/bar"
 	"github.com/soumya92/barista/base"
@@ -29,6 +31,11 @@ import (
 	l "github.com/soumya92/barista/logging"
 )
 
+// signalRefreshInterval is how often wifi info is refreshed while
+// connected, absent any errors. base.Retry backs off beyond this on
+// failure, and resets to it as soon as a fetch succeeds again.
+const signalRefreshInterval = 5 * time.Second
+
 // Info represents the wireless card status.
 type Info struct {
 	Name           string
@@ -38,6 +45,28 @@ type Info struct {
 	AccessPointMAC string
 	Channel        int
 	Frequency      float64
+
+	// SignalDBM is the received signal strength of the current connection,
+	// in dBm. Zero if not connected or unavailable (e.g. iwgetid fallback).
+	SignalDBM int
+	// SignalPercent is SignalDBM rescaled to 0-100, using the same -50/-100
+	// dBm range as NetworkManager.
+	SignalPercent int
+	// NoiseDBM is the measured noise floor of the channel currently in use,
+	// in dBm, from the kernel's channel survey. Zero if the driver doesn't
+	// survey (or the iwgetid fallback is in use, which never reports it).
+	NoiseDBM int
+	// BitrateMbps is the current tx bitrate, in megabits per second.
+	BitrateMbps float64
+	// TxPower is the configured transmit power, in dBm. Zero if the
+	// iwgetid fallback is in use, which doesn't report it.
+	TxPower float64
+	// Security describes the authentication/encryption in use.
+	Security Security
+
+	// Stale is true if the most recent refresh of the fields above failed,
+	// so the values shown are from an earlier, successful fetch.
+	Stale bool
 }
 
 // Connecting returns true if a connection is in progress.
@@ -59,6 +88,12 @@ func (i Info) Enabled() bool {
 type Module struct {
 	intf       string
 	outputFunc base.Value // of func(Info) bar.Output
+
+	drvOnce sync.Once
+	drv     driver
+
+	retry     *base.Retryable
+	retryTick bar.Ticker
 }
 
 // Named constructs an instance of the wlan module for the specified interface.
@@ -100,33 +135,84 @@ func (m *Module) Stream(s bar.Sink) {
 		updateChan = netlink.ByName(m.intf)
 	}
 	defer updateChan.Unsubscribe()
+	defer m.stopRetry()
 	for {
+		changed := true
 		select {
 		case update := <-updateChan:
+			m.stopRetry()
 			info = Info{
 				Name:  update.Name,
 				State: update.State,
 				IPs:   update.IPs,
 			}
-			fillWifiInfo(&info)
+			if info.Connected() {
+				m.startRetry(info.Name)
+			}
+
+		case <-m.retryTicker():
+			v, err := m.retry.Get()
+			fetched, ok := v.(Info)
+			if !ok {
+				changed = false
+				break
+			}
+			// The retried fetch only knows the fields it filled in; carry
+			// over the link state from the netlink watcher.
+			fetched.Name, fetched.State, fetched.IPs = info.Name, info.State, info.IPs
+			fetched.Stale = err != nil
+			info = fetched
+
 		case <-m.outputFunc.Update():
 			outputFunc = m.outputFunc.Get().(func(Info) bar.Output)
 		}
-		s.Output(outputFunc(info))
+		if changed {
+			s.Output(outputFunc(info))
+		}
 	}
 }
 
-func fillWifiInfo(info *Info) {
-	ssid, err := iwgetid(info.Name, "-r")
-	if err != nil {
+// startRetry begins (re)fetching the nl80211/iwgetid fields for name on a
+// resilient, backed-off schedule.
+func (m *Module) startRetry(name string) {
+	m.retry = base.Retry(func() (interface{}, error) {
+		info := Info{Name: name, State: netlink.Up}
+		err := m.fillWifiInfo(&info)
+		return info, err
+	}, base.RetryOptions{InitialInterval: signalRefreshInterval, Label: "wlan." + name})
+	m.retryTick = m.retry.Subscribe()
+}
+
+// stopRetry tears down any in-flight retry, e.g. because the link went
+// down or changed identity.
+func (m *Module) stopRetry() {
+	if m.retry == nil {
 		return
 	}
-	info.SSID = ssid
-	info.AccessPointMAC, _ = iwgetid(info.Name, "-a")
-	ch, _ := iwgetid(info.Name, "-c")
-	info.Channel, _ = strconv.Atoi(ch)
-	freq, _ := iwgetid(info.Name, "-f")
-	info.Frequency, _ = strconv.ParseFloat(freq, 64)
+	m.retry.Stop()
+	m.retry, m.retryTick = nil, nil
+}
+
+// retryTicker returns the retry's tick channel, or nil (which blocks
+// forever in a select, as intended) if there's no retry in flight.
+func (m *Module) retryTicker() <-chan struct{} {
+	if m.retryTick == nil {
+		return nil
+	}
+	return m.retryTick.Tick()
+}
+
+// fillWifiInfo fills in the fields iwgetid/nl80211 can provide, lazily
+// picking and caching whichever backend is available for this module.
+func (m *Module) fillWifiInfo(info *Info) error {
+	m.drvOnce.Do(func() {
+		if nlDrv, err := newNetlinkDriver(); err == nil {
+			m.drv = nlDrv
+		} else {
+			m.drv = iwgetidDriver{}
+		}
+	})
+	return m.drv.fillWifiInfo(info)
 }
 
 var iwgetid = func(intf, flag string) (string, error) {